@@ -0,0 +1,90 @@
+package automaticloggingprocessor
+
+import (
+	"errors"
+	"time"
+)
+
+// Backend-facing defaults. These mirror the defaults used elsewhere in the agent for
+// similarly-shaped backlog/queue configuration.
+const (
+	defaultBacklog        = 100
+	defaultProcessesFlush = 10 * time.Second
+	spanAttributeTraceID  = "trace_id"
+	spanAttributeSpanID   = "span_id"
+	spanAttributeParentID = "parent_id"
+	spanAttributeSvcName  = "svc"
+	spanAttributeSpanName = "span"
+	spanAttributeDuration = "dur"
+	spanAttributeStatus   = "status"
+
+	// resourceAttributeTenantID is the resource attribute automatic labels derive the
+	// "tenant" Loki label from, when present.
+	resourceAttributeTenantID = "tenant.id"
+)
+
+// AutomaticLoggingConfig holds config information for automatic logging
+type AutomaticLoggingConfig struct {
+	// LokiName is the name of the Loki client (registered elsewhere in the agent) that
+	// log lines should be forwarded to.
+	LokiName string `mapstructure:"loki_name" yaml:"loki_name"`
+
+	// Spans logs one line per span.
+	Spans bool `mapstructure:"spans" yaml:"spans"`
+	// Roots logs one line per root span (a span with no parent).
+	Roots bool `mapstructure:"roots" yaml:"roots"`
+	// Process logs one deduplicated line per service/resource combination on an interval.
+	Process bool `mapstructure:"process" yaml:"process"`
+
+	// ProcessesFlushInterval controls how often deduplicated process log lines are
+	// emitted when Process is true. Defaults to 10s.
+	ProcessesFlushInterval time.Duration `mapstructure:"processes_flush_interval" yaml:"processes_flush_interval"`
+
+	// SpanAttributes is an allowlist of span attribute keys to include on every log line.
+	SpanAttributes []string `mapstructure:"span_attributes" yaml:"span_attributes"`
+	// ProcessAttributes is an allowlist of resource attribute keys to include on every log line.
+	ProcessAttributes []string `mapstructure:"process_attributes" yaml:"process_attributes"`
+
+	// Labels is a static set of Loki labels to apply to every log line. In addition to
+	// these, "tenant" and "service" labels are derived automatically from the span's
+	// resource attributes.
+	Labels map[string]string `mapstructure:"labels" yaml:"labels"`
+
+	// Backlog is the size of the queue used to buffer log lines before they're pushed
+	// to Loki. Lines are dropped (and a drop metric incremented) when the queue is full.
+	Backlog int `mapstructure:"backlog" yaml:"backlog"`
+}
+
+// Validate confirms the config is well formed and fills in defaults.
+func (c *AutomaticLoggingConfig) Validate() error {
+	if c == nil {
+		return errors.New("automatic_logging config is required")
+	}
+
+	if len(c.LokiName) == 0 {
+		return errors.New("must specify a loki_name to log to")
+	}
+
+	modes := 0
+	for _, b := range []bool{c.Spans, c.Roots, c.Process} {
+		if b {
+			modes++
+		}
+	}
+	if modes == 0 {
+		return errors.New("must specify one of spans, roots or process")
+	}
+	if modes > 1 {
+		return errors.New("only one of spans, roots or process may be specified")
+	}
+
+	if c.Backlog <= 0 {
+		c.Backlog = defaultBacklog
+	}
+
+	if c.ProcessesFlushInterval <= 0 {
+		c.ProcessesFlushInterval = defaultProcessesFlush
+	}
+
+	return nil
+}
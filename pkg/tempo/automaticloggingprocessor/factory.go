@@ -19,11 +19,6 @@ type Config struct {
 	LoggingConfig *AutomaticLoggingConfig `mapstructure:"automatic_logging"`
 }
 
-// AutomaticLoggingConfig holds config information for automatic logging
-type AutomaticLoggingConfig struct {
-	LokiName string `mapstructure:"loki_name" yaml:"loki_name"`
-}
-
 // NewFactory returns a new factory for the Attributes processor.
 func NewFactory() component.ProcessorFactory {
 	return processorhelper.NewFactory(
@@ -50,5 +45,5 @@ func createTraceProcessor(
 ) (component.TracesProcessor, error) {
 	oCfg := cfg.(*Config)
 
-	return newTraceProcessor(nextConsumer, oCfg.LoggingConfig)
+	return newTraceProcessor(nextConsumer, cp.Logger, oCfg.LoggingConfig)
 }
@@ -0,0 +1,51 @@
+package automaticloggingprocessor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// LokiClient is the interface required of a Loki client capable of receiving log lines
+// pushed by this processor. It's implemented by the agent's own Loki instances, which
+// register themselves via RegisterLokiClient.
+type LokiClient interface {
+	Push(labels model.LabelSet, ts time.Time, line string) error
+}
+
+var (
+	lokiClientsMtx sync.Mutex
+	lokiClients    = map[string]LokiClient{}
+)
+
+// RegisterLokiClient registers a named Loki client that automatic_logging_processor
+// instances can forward log lines to via their loki_name configuration. It's expected
+// to be called by the Loki subsystem as instances are created.
+func RegisterLokiClient(name string, c LokiClient) {
+	lokiClientsMtx.Lock()
+	defer lokiClientsMtx.Unlock()
+
+	lokiClients[name] = c
+}
+
+// UnregisterLokiClient removes a previously registered Loki client.
+func UnregisterLokiClient(name string) {
+	lokiClientsMtx.Lock()
+	defer lokiClientsMtx.Unlock()
+
+	delete(lokiClients, name)
+}
+
+func lokiClientFor(name string) (LokiClient, error) {
+	lokiClientsMtx.Lock()
+	defer lokiClientsMtx.Unlock()
+
+	c, ok := lokiClients[name]
+	if !ok {
+		return nil, fmt.Errorf("no loki client named %s is registered with the agent", name)
+	}
+
+	return c, nil
+}
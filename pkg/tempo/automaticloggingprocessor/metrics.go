@@ -0,0 +1,13 @@
+package automaticloggingprocessor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var metricDroppedSpans = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Subsystem: "automatic_logging_processor",
+	Name:      "dropped_spans_total",
+	Help:      "Total number of spans dropped because the log backlog was full",
+}, []string{"loki_name"})
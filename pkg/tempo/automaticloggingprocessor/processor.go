@@ -0,0 +1,292 @@
+package automaticloggingprocessor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.uber.org/zap"
+)
+
+// logLine is a single line queued up to be pushed to Loki.
+type logLine struct {
+	labels model.LabelSet
+	ts     time.Time
+	line   string
+}
+
+// automaticLoggingProcessor converts spans passing through a tempo pipeline into log
+// lines and forwards them to a Loki client registered elsewhere in the agent.
+type automaticLoggingProcessor struct {
+	next   consumer.TracesConsumer
+	cfg    *AutomaticLoggingConfig
+	logger *zap.Logger
+	loki   LokiClient
+
+	queue chan logLine
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	// processMu/seenProcesses/ticker support the "process" mode, which dedupes log
+	// lines per service/resource and flushes them on an interval.
+	processMu     sync.Mutex
+	seenProcesses map[string]logLine
+	processTicker *time.Ticker
+}
+
+func newTraceProcessor(next consumer.TracesConsumer, logger *zap.Logger, cfg *AutomaticLoggingConfig) (component.TracesProcessor, error) {
+	if next == nil {
+		return nil, errMissingNextConsumer
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	loki, err := lokiClientFor(cfg.LokiName)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &automaticLoggingProcessor{
+		next:   next,
+		cfg:    cfg,
+		logger: logger,
+		loki:   loki,
+		queue:  make(chan logLine, cfg.Backlog),
+		done:   make(chan struct{}),
+	}
+
+	if cfg.Process {
+		p.seenProcesses = map[string]logLine{}
+		p.processTicker = time.NewTicker(cfg.ProcessesFlushInterval)
+	}
+
+	return p, nil
+}
+
+var errMissingNextConsumer = fmt.Errorf("automatic_logging_processor: nextConsumer is required")
+
+// Start implements component.Component.
+func (p *automaticLoggingProcessor) Start(_ context.Context, _ component.Host) error {
+	p.wg.Add(1)
+	go p.loop()
+
+	if p.processTicker != nil {
+		p.wg.Add(1)
+		go p.flushProcessesLoop()
+	}
+
+	return nil
+}
+
+// Shutdown implements component.Component.
+func (p *automaticLoggingProcessor) Shutdown(_ context.Context) error {
+	close(p.done)
+	if p.processTicker != nil {
+		p.processTicker.Stop()
+	}
+	p.wg.Wait()
+	return nil
+}
+
+// GetCapabilities implements component.Processor. The processor only reads spans to
+// produce log lines, so it never mutates data and can safely run in parallel with
+// exporters.
+func (p *automaticLoggingProcessor) GetCapabilities() component.ProcessorCapabilities {
+	return component.ProcessorCapabilities{MutatesConsumedData: false}
+}
+
+// ConsumeTraces implements consumer.TracesConsumer.
+func (p *automaticLoggingProcessor) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		resourceAttrs := attributesToMap(rs.Resource().Attributes())
+		serviceName := resourceAttrs["service.name"]
+
+		ilss := rs.InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				p.consumeSpan(spans.At(k), serviceName, resourceAttrs)
+			}
+		}
+	}
+
+	return p.next.ConsumeTraces(ctx, td)
+}
+
+func (p *automaticLoggingProcessor) consumeSpan(span pdata.Span, serviceName string, resourceAttrs map[string]string) {
+	isRoot := !span.ParentSpanID().IsValid()
+
+	switch {
+	case p.cfg.Roots && !isRoot:
+		return
+	case p.cfg.Process:
+		p.recordProcess(serviceName, resourceAttrs)
+		return
+	}
+
+	line := p.formatSpanLine(span, serviceName, resourceAttrs)
+	p.enqueue(logLine{
+		labels: p.labelsFor(serviceName, resourceAttrs),
+		ts:     timestampToTime(span.EndTime()),
+		line:   line,
+	})
+}
+
+func (p *automaticLoggingProcessor) recordProcess(serviceName string, resourceAttrs map[string]string) {
+	key := serviceName
+	for _, attr := range p.cfg.ProcessAttributes {
+		key += "|" + attr + "=" + resourceAttrs[attr]
+	}
+
+	line := p.formatProcessLine(serviceName, resourceAttrs)
+
+	p.processMu.Lock()
+	defer p.processMu.Unlock()
+	p.seenProcesses[key] = logLine{
+		labels: p.labelsFor(serviceName, resourceAttrs),
+		ts:     time.Now(),
+		line:   line,
+	}
+}
+
+func (p *automaticLoggingProcessor) flushProcessesLoop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.processTicker.C:
+			p.flushProcesses()
+		}
+	}
+}
+
+func (p *automaticLoggingProcessor) flushProcesses() {
+	p.processMu.Lock()
+	toFlush := p.seenProcesses
+	p.seenProcesses = map[string]logLine{}
+	p.processMu.Unlock()
+
+	for _, l := range toFlush {
+		p.enqueue(l)
+	}
+}
+
+func (p *automaticLoggingProcessor) formatSpanLine(span pdata.Span, serviceName string, resourceAttrs map[string]string) string {
+	parts := []string{
+		spanAttributeTraceID + "=" + span.TraceID().HexString(),
+		spanAttributeSpanID + "=" + span.SpanID().HexString(),
+	}
+
+	if span.ParentSpanID().IsValid() {
+		parts = append(parts, spanAttributeParentID+"="+span.ParentSpanID().HexString())
+	}
+
+	parts = append(parts,
+		spanAttributeSvcName+"="+serviceName,
+		spanAttributeSpanName+"="+span.Name(),
+		spanAttributeStatus+"="+span.Status().Code().String(),
+		spanAttributeDuration+"="+timestampToTime(span.EndTime()).Sub(timestampToTime(span.StartTime())).String(),
+	)
+
+	spanAttrs := attributesToMap(span.Attributes())
+	for _, key := range p.cfg.SpanAttributes {
+		if v, ok := spanAttrs[key]; ok {
+			parts = append(parts, key+"="+v)
+		}
+	}
+	for _, key := range p.cfg.ProcessAttributes {
+		if v, ok := resourceAttrs[key]; ok {
+			parts = append(parts, key+"="+v)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (p *automaticLoggingProcessor) formatProcessLine(serviceName string, resourceAttrs map[string]string) string {
+	parts := []string{spanAttributeSvcName + "=" + serviceName}
+
+	for _, key := range p.cfg.ProcessAttributes {
+		if v, ok := resourceAttrs[key]; ok {
+			parts = append(parts, key+"="+v)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (p *automaticLoggingProcessor) labelsFor(serviceName string, resourceAttrs map[string]string) model.LabelSet {
+	set := model.LabelSet{}
+	for k, v := range p.cfg.Labels {
+		set[model.LabelName(k)] = model.LabelValue(v)
+	}
+	set["service"] = model.LabelValue(serviceName)
+	if tenant, ok := resourceAttrs[resourceAttributeTenantID]; ok && len(tenant) > 0 {
+		set["tenant"] = model.LabelValue(tenant)
+	}
+
+	return set
+}
+
+func (p *automaticLoggingProcessor) enqueue(l logLine) {
+	select {
+	case p.queue <- l:
+	default:
+		metricDroppedSpans.WithLabelValues(p.cfg.LokiName).Inc()
+	}
+}
+
+func (p *automaticLoggingProcessor) loop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case l := <-p.queue:
+			if err := p.loki.Push(l.labels, l.ts, l.line); err != nil {
+				p.logger.Error("failed to push log line to loki", zap.String("loki_name", p.cfg.LokiName), zap.Error(err))
+			}
+		}
+	}
+}
+
+func attributesToMap(attrs pdata.AttributeMap) map[string]string {
+	m := make(map[string]string, attrs.Len())
+	attrs.ForEach(func(k string, v pdata.AttributeValue) {
+		m[k] = attributeValueToString(v)
+	})
+	return m
+}
+
+func attributeValueToString(v pdata.AttributeValue) string {
+	switch v.Type() {
+	case pdata.AttributeValueSTRING:
+		return v.StringVal()
+	case pdata.AttributeValueBOOL:
+		return strconv.FormatBool(v.BoolVal())
+	case pdata.AttributeValueINT:
+		return strconv.FormatInt(v.IntVal(), 10)
+	case pdata.AttributeValueDOUBLE:
+		return strconv.FormatFloat(v.DoubleVal(), 'f', -1, 64)
+	default:
+		return v.StringVal()
+	}
+}
+
+func timestampToTime(ts pdata.TimestampUnixNano) time.Time {
+	return time.Unix(0, int64(ts))
+}
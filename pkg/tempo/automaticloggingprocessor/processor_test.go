@@ -0,0 +1,169 @@
+package automaticloggingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.uber.org/zap"
+)
+
+type fakeLokiClient struct {
+	pushed []string
+}
+
+func (f *fakeLokiClient) Push(_ model.LabelSet, _ time.Time, line string) error {
+	f.pushed = append(f.pushed, line)
+	return nil
+}
+
+func testTraces(serviceName, spanName string, parentID []byte) pdata.Traces {
+	td := pdata.NewTraces()
+	td.ResourceSpans().Resize(1)
+	rs := td.ResourceSpans().At(0)
+	rs.Resource().InitEmpty()
+	rs.Resource().Attributes().InsertString("service.name", serviceName)
+
+	rs.InstrumentationLibrarySpans().Resize(1)
+	ils := rs.InstrumentationLibrarySpans().At(0)
+	ils.Spans().Resize(1)
+
+	span := ils.Spans().At(0)
+	span.SetTraceID(pdata.NewTraceID([16]byte{1}))
+	span.SetSpanID(pdata.NewSpanID([8]byte{1}))
+	if len(parentID) > 0 {
+		var id [8]byte
+		copy(id[:], parentID)
+		span.SetParentSpanID(pdata.NewSpanID(id))
+	}
+	span.SetName(spanName)
+	span.SetStartTime(pdata.TimestampUnixNano(1000))
+	span.SetEndTime(pdata.TimestampUnixNano(2000))
+
+	return td
+}
+
+func newTestProcessor(t *testing.T, cfg *AutomaticLoggingConfig) (*automaticLoggingProcessor, *fakeLokiClient) {
+	t.Helper()
+
+	client := &fakeLokiClient{}
+	RegisterLokiClient("test", client)
+	t.Cleanup(func() { UnregisterLokiClient("test") })
+
+	cfg.LokiName = "test"
+
+	proc, err := newTraceProcessor(consumertest.NewTracesNop(), zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	return proc.(*automaticLoggingProcessor), client
+}
+
+func TestConsumeTracesSpansMode(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Spans: true})
+
+	err := proc.ConsumeTraces(context.Background(), testTraces("my-service", "my-span", nil))
+	require.NoError(t, err)
+
+	require.Len(t, proc.queue, 1)
+	line := <-proc.queue
+	assert.Contains(t, line.line, "svc=my-service")
+	assert.Contains(t, line.line, "span=my-span")
+	assert.Contains(t, line.line, "trace_id=")
+	assert.Contains(t, line.line, "span_id=")
+}
+
+// TestConsumeTracesPushesToLokiClient starts the processor for real, rather than
+// reaching into proc.queue directly, so it exercises loop() dequeuing and calling
+// loki.Push - the path that actually gets a span-derived log line to Loki.
+func TestConsumeTracesPushesToLokiClient(t *testing.T) {
+	proc, client := newTestProcessor(t, &AutomaticLoggingConfig{Spans: true})
+
+	require.NoError(t, proc.Start(context.Background(), nil))
+	defer func() { require.NoError(t, proc.Shutdown(context.Background())) }()
+
+	err := proc.ConsumeTraces(context.Background(), testTraces("my-service", "my-span", nil))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(client.pushed) == 1 }, time.Second, time.Millisecond)
+	assert.Contains(t, client.pushed[0], "svc=my-service")
+	assert.Contains(t, client.pushed[0], "span=my-span")
+}
+
+func TestConsumeTracesRootsModeSkipsChildSpans(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Roots: true})
+
+	err := proc.ConsumeTraces(context.Background(), testTraces("my-service", "child-span", []byte{2, 2, 2, 2, 2, 2, 2, 2}))
+	require.NoError(t, err)
+
+	assert.Len(t, proc.queue, 0)
+}
+
+func TestConsumeTracesRootsModeKeepsRootSpans(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Roots: true})
+
+	err := proc.ConsumeTraces(context.Background(), testTraces("my-service", "root-span", nil))
+	require.NoError(t, err)
+
+	require.Len(t, proc.queue, 1)
+}
+
+func TestConsumeTracesProcessModeDedupes(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Process: true})
+
+	err := proc.ConsumeTraces(context.Background(), testTraces("my-service", "span-a", nil))
+	require.NoError(t, err)
+	err = proc.ConsumeTraces(context.Background(), testTraces("my-service", "span-b", nil))
+	require.NoError(t, err)
+
+	assert.Len(t, proc.seenProcesses, 1)
+
+	proc.flushProcesses()
+	require.Len(t, proc.queue, 1)
+}
+
+func TestValidateRequiresExactlyOneMode(t *testing.T) {
+	cfg := &AutomaticLoggingConfig{LokiName: "test"}
+	assert.Error(t, cfg.Validate())
+
+	cfg.Spans = true
+	cfg.Roots = true
+	assert.Error(t, cfg.Validate())
+
+	cfg.Roots = false
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestNewTraceProcessorRequiresRegisteredLokiClient(t *testing.T) {
+	_, err := newTraceProcessor(consumertest.NewTracesNop(), zap.NewNop(), &AutomaticLoggingConfig{
+		LokiName: "does-not-exist",
+		Spans:    true,
+	})
+	assert.Error(t, err)
+}
+
+func TestGetCapabilitiesDoesNotMutate(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Spans: true})
+	assert.False(t, proc.GetCapabilities().MutatesConsumedData)
+}
+
+func TestLabelsForIncludesTenantWhenPresent(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Spans: true, Labels: map[string]string{"static": "value"}})
+
+	labels := proc.labelsFor("my-service", map[string]string{resourceAttributeTenantID: "my-tenant"})
+	assert.Equal(t, model.LabelValue("my-service"), labels["service"])
+	assert.Equal(t, model.LabelValue("my-tenant"), labels["tenant"])
+	assert.Equal(t, model.LabelValue("value"), labels["static"])
+}
+
+func TestLabelsForOmitsTenantWhenAbsent(t *testing.T) {
+	proc, _ := newTestProcessor(t, &AutomaticLoggingConfig{Spans: true})
+
+	labels := proc.labelsFor("my-service", map[string]string{})
+	_, ok := labels["tenant"]
+	assert.False(t, ok)
+}
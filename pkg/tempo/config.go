@@ -1,21 +1,17 @@
 package tempo
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"sort"
+	"strings"
 
-	"github.com/spf13/viper"
-	"go.opentelemetry.io/collector/component"
-	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/config/configmodels"
-	"go.opentelemetry.io/collector/exporter/otlpexporter"
 	"go.opentelemetry.io/collector/processor/batchprocessor"
 	"go.opentelemetry.io/collector/processor/queuedprocessor"
-	"go.opentelemetry.io/collector/receiver/jaegerreceiver"
-	"go.opentelemetry.io/collector/receiver/otlpreceiver"
-	"go.opentelemetry.io/collector/receiver/zipkinreceiver"
 )
 
 // Config controls the configuration of the Tempo trace pipeline.
@@ -23,10 +19,42 @@ type Config struct {
 	// Whether the Tempo subsystem should be enabled.
 	Enabled bool `yaml:"-"`
 
-	RemoteWrite RWConfig `yaml:"remote_write"`
-
 	// Receivers: https://github.com/open-telemetry/opentelemetry-collector/tree/master/receiver
+	// These are shared by name across all Pipelines below.
 	Receivers map[string]interface{} `yaml:"receivers"`
+
+	// Pipelines is the set of independent trace pipelines the agent runs. Each
+	// pipeline has its own exporter (and processor chain) but may reference any of
+	// the receivers defined above, so e.g. a single jaeger receiver can feed both a
+	// Grafana Cloud pipeline and a local archival pipeline.
+	Pipelines []PipelineConfig `yaml:"pipelines"`
+
+	// Extensions: https://github.com/open-telemetry/opentelemetry-collector/tree/master/extension
+	// Unlike Receivers/Pipelines these aren't part of any one trace pipeline -
+	// they're collector-wide diagnostics (zpages, health_check, ...) started once
+	// alongside the pipelines.
+	Extensions map[string]interface{} `yaml:"extensions"`
+
+	// ConfigProvider lets advanced users extend how each pipeline's otel config is
+	// assembled, e.g. to pull in additional confmap Providers/Converters (remote
+	// secrets, env expansion, includes). It's not set via YAML; callers wire it up
+	// in code.
+	ConfigProvider ConfigProviderSettings `yaml:"-"`
+}
+
+// PipelineConfig configures a single named trace pipeline: the receivers that feed
+// it and where/how it exports.
+type PipelineConfig struct {
+	// Name uniquely identifies this pipeline among its siblings. It namespaces the
+	// pipeline's own exporter/processors in the underlying otel config, and is used
+	// for logging.
+	Name string `yaml:"name"`
+
+	// Receivers lists the names of receivers (defined in Config.Receivers) that
+	// feed this pipeline.
+	Receivers []string `yaml:"receivers"`
+
+	RemoteWrite RWConfig `yaml:"remote_write"`
 }
 
 // RWConfig controls the configuration of exporting to Grafana Cloud
@@ -54,133 +82,263 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return unmarshal((*plain)(c))
 }
 
-func (c *Config) otelConfig() (*configmodels.Config, error) {
-	otelMapStructure := map[string]interface{}{}
-
+// Validate confirms the config is well formed: every pipeline has a unique name, at
+// least one receiver, and somewhere to export to.
+func (c *Config) Validate() error {
 	if !c.Enabled {
-		return nil, errors.New("tempo config not enabled")
+		return errors.New("tempo config not enabled")
 	}
 
 	if len(c.Receivers) == 0 {
-		return nil, errors.New("must have at least one configured receiver")
+		return errors.New("must have at least one configured receiver")
 	}
 
-	if len(c.RemoteWrite.URL) == 0 {
-		return nil, errors.New("must have a configured remote_write.url")
+	if len(c.Pipelines) == 0 {
+		return errors.New("must have at least one configured pipeline")
 	}
 
-	// exporter
-	var headers map[string]string
-	if c.RemoteWrite.BasicAuth != nil {
-		password := c.RemoteWrite.BasicAuth.Password
+	seen := map[string]bool{}
+	for _, p := range c.Pipelines {
+		if len(p.Name) == 0 {
+			return errors.New("every pipeline must have a name")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate pipeline name %q", p.Name)
+		}
+		seen[p.Name] = true
 
-		if len(c.RemoteWrite.BasicAuth.PasswordFile) > 0 {
-			buff, err := ioutil.ReadFile(c.RemoteWrite.BasicAuth.PasswordFile)
-			if err != nil {
-				return nil, fmt.Errorf("unable to load password file %s %w", c.RemoteWrite.BasicAuth.PasswordFile, err)
-			}
-			password = string(buff)
+		if len(p.Receivers) == 0 {
+			return fmt.Errorf("pipeline %q must reference at least one receiver", p.Name)
+		}
+		if len(p.RemoteWrite.URL) == 0 {
+			return fmt.Errorf("pipeline %q must have a configured remote_write.url", p.Name)
 		}
+	}
+
+	return nil
+}
 
-		encodedAuth := base64.StdEncoding.EncodeToString([]byte(c.RemoteWrite.BasicAuth.Username + ":" + password))
-		headers = map[string]string{
-			"Authorization": "Basic " + encodedAuth,
+// pipelineComponents groups c.Pipelines by shared receivers: two pipelines land in
+// the same group if they reference any receiver name in common, directly or
+// transitively through a third pipeline. Every receiver in a group fans out to every
+// pipeline in that same group, so they all have to be built, rebuilt and torn down
+// together - Tempo.Reload uses this to scope a rebuild down to the one group that
+// actually changed, leaving every other group's receivers/pipelines running.
+func (c *Config) pipelineComponents() [][]string {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(x string) string {
+		if _, ok := parent[x]; !ok {
+			parent[x] = x
+		}
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[ra] = rb
 		}
 	}
 
-	otelMapStructure["exporters"] = map[string]interface{}{
-		"otlp": map[string]interface{}{
-			"endpoint": c.RemoteWrite.URL,
-			"headers":  headers,
-		},
+	for _, p := range c.Pipelines {
+		find("pipeline:" + p.Name)
+		for _, r := range p.Receivers {
+			union("pipeline:"+p.Name, "receiver:"+r)
+		}
 	}
 
-	// processors
-	processors := map[string]interface{}{}
-	processorNames := []string{}
-	if c.RemoteWrite.Batch != nil {
-		processors["batch"] = c.RemoteWrite.Batch
-		processorNames = append(processorNames, "batch")
+	groups := map[string][]string{}
+	for _, p := range c.Pipelines {
+		root := find("pipeline:" + p.Name)
+		groups[root] = append(groups[root], p.Name)
 	}
 
-	if c.RemoteWrite.Queue != nil {
-		processors["queue"] = c.RemoteWrite.Queue
-		processorNames = append(processorNames, "queue")
+	out := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group)
+		out = append(out, group)
 	}
-	otelMapStructure["processors"] = processors
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
 
-	// receivers
-	otelMapStructure["receivers"] = c.Receivers
-	receiverNames := []string{}
-	for name := range c.Receivers {
-		receiverNames = append(receiverNames, name)
+	return out
+}
+
+// pipelineSnapshot is the portion of Config relevant to one pipelineComponents group:
+// the PipelineConfig for every pipeline in it, plus the raw config of every receiver
+// any of them reference. Tempo.Reload compares two snapshots (rather than the whole
+// Config) to decide whether a given group actually needs rebuilding.
+type pipelineSnapshot struct {
+	Pipelines []PipelineConfig
+	Receivers map[string]interface{}
+}
+
+func (c *Config) snapshotFor(names []string) pipelineSnapshot {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
 	}
 
-	// pipelines
-	otelMapStructure["pipelines"] = map[string]interface{}{
-		"traces": map[string]interface{}{
-			"exporters":  []string{"otlp"},
-			"processors": processorNames,
-			"receivers":  receiverNames,
-		},
+	snap := pipelineSnapshot{Receivers: map[string]interface{}{}}
+	for _, p := range c.Pipelines {
+		if !want[p.Name] {
+			continue
+		}
+		snap.Pipelines = append(snap.Pipelines, p)
+		for _, r := range p.Receivers {
+			snap.Receivers[r] = c.Receivers[r]
+		}
 	}
+	sort.Slice(snap.Pipelines, func(i, j int) bool { return snap.Pipelines[i].Name < snap.Pipelines[j].Name })
 
-	// now build the otel configmodel from the mapstructure
-	v := viper.New()
-	err := v.MergeConfigMap(otelMapStructure)
-	if err != nil {
-		return nil, fmt.Errorf("failed to merge in mapstructure config %w", err)
+	return snap
+}
+
+// otelConfig builds a single otel configmodels.Config covering every configured
+// pipeline at once. Each PipelineConfig gets its own namespaced exporter, processor
+// chain and "traces/<name>" otel pipeline, but all of them share one Receivers
+// section - that's what lets two pipelines reference the same receiver name and have
+// it built (and bound to its listen address) exactly once, fanning out to both,
+// instead of each pipeline standing up its own competing instance.
+func (c *Config) otelConfig(reg *Registry) (*configmodels.Config, error) {
+	return c.otelConfigFor(reg, nil)
+}
+
+// otelConfigFor is otelConfig scoped to a subset of c.Pipelines - names is the set of
+// PipelineConfig.Name to include, or nil to include all of them. Tempo.Reload uses
+// this to rebuild just the pipelines (and the receivers they share) affected by a
+// config change, leaving everything else running undisturbed; see
+// Config.pipelineComponents.
+func (c *Config) otelConfigFor(reg *Registry, names map[string]bool) (*configmodels.Config, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
 	}
 
-	factories, err := tracingFactories()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create factories %w", err)
+	exporters := map[string]interface{}{}
+	processors := map[string]interface{}{}
+	pipelines := map[string]interface{}{}
+	receivers := map[string]interface{}{}
+	receiverTypes := map[configmodels.Type]struct{}{}
+
+	for _, p := range c.Pipelines {
+		if names != nil && !names[p.Name] {
+			continue
+		}
+
+		exporterName := "otlp/" + p.Name
+
+		var headers map[string]string
+		if p.RemoteWrite.BasicAuth != nil {
+			password := p.RemoteWrite.BasicAuth.Password
+
+			if len(p.RemoteWrite.BasicAuth.PasswordFile) > 0 {
+				buff, err := ioutil.ReadFile(p.RemoteWrite.BasicAuth.PasswordFile)
+				if err != nil {
+					return nil, fmt.Errorf("unable to load password file %s %w", p.RemoteWrite.BasicAuth.PasswordFile, err)
+				}
+				password = string(buff)
+			}
+
+			encodedAuth := base64.StdEncoding.EncodeToString([]byte(p.RemoteWrite.BasicAuth.Username + ":" + password))
+			headers = map[string]string{
+				"Authorization": "Basic " + encodedAuth,
+			}
+		}
+
+		exporters[exporterName] = map[string]interface{}{
+			"endpoint": p.RemoteWrite.URL,
+			"headers":  headers,
+		}
+
+		processorNames := []string{}
+		if p.RemoteWrite.Batch != nil {
+			name := "batch/" + p.Name
+			processors[name] = p.RemoteWrite.Batch
+			processorNames = append(processorNames, name)
+		}
+		if p.RemoteWrite.Queue != nil {
+			name := "queue/" + p.Name
+			processors[name] = p.RemoteWrite.Queue
+			processorNames = append(processorNames, name)
+		}
+
+		for _, name := range p.Receivers {
+			cfg, ok := c.Receivers[name]
+			if !ok {
+				return nil, fmt.Errorf("pipeline %q references unknown receiver %q", p.Name, name)
+			}
+			receivers[name] = cfg
+			receiverTypes[configmodels.Type(componentTypeName(name))] = struct{}{}
+		}
+
+		pipelines["traces/"+p.Name] = map[string]interface{}{
+			"exporters":  []string{exporterName},
+			"processors": processorNames,
+			"receivers":  p.Receivers,
+		}
 	}
 
-	otelCfg, err := config.Load(v, factories)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load OTel config %w", err)
+	typs := make([]configmodels.Type, 0, len(receiverTypes))
+	for typ := range receiverTypes {
+		typs = append(typs, typ)
+	}
+	if err := reg.ValidateReceiverTypes(typs); err != nil {
+		return nil, err
 	}
 
-	return otelCfg, nil
-}
+	otelMapStructure := map[string]interface{}{
+		"exporters":  exporters,
+		"processors": processors,
+		"receivers":  receivers,
+		"pipelines":  pipelines,
+	}
 
-// tracingFactories() only creates the needed factories.  if we decide to add support for a new
-// processor, exporter, receiver we need to add it here
-func tracingFactories() (config.Factories, error) {
-	extensions, err := component.MakeExtensionFactoryMap()
-	if err != nil {
-		return config.Factories{}, err
+	// The agent's own tempo: YAML block is the base of the confmap. Any configured
+	// Providers are merged on top of it (e.g. file includes, remote secret stores),
+	// and Converters run last (e.g. ${ENV_VAR} expansion). This is the only place
+	// new otel components require a code change to support - everything else is
+	// factory registration, see Registry.
+	ctx := context.Background()
+	conf := NewConfFromStringMap(otelMapStructure)
+
+	for _, provider := range c.ConfigProvider.Providers {
+		retrieved, err := provider.Retrieve(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve confmap from provider %w", err)
+		}
+		conf.Merge(retrieved)
 	}
 
-	receivers, err := component.MakeReceiverFactoryMap(
-		jaegerreceiver.NewFactory(),
-		&zipkinreceiver.Factory{},
-		otlpreceiver.NewFactory(), // jpe - opencensus?
-	)
-	if err != nil {
-		return config.Factories{}, err
+	converters := c.ConfigProvider.Converters
+	if len(converters) == 0 {
+		converters = defaultConverters()
+	}
+	for _, conv := range converters {
+		if err := conv.Convert(ctx, conf); err != nil {
+			return nil, fmt.Errorf("failed to convert confmap %w", err)
+		}
 	}
 
-	exporters, err := component.MakeExporterFactoryMap(
-		&otlpexporter.Factory{},
-	)
+	factories, err := reg.Factories()
 	if err != nil {
-		return config.Factories{}, err
+		return nil, fmt.Errorf("failed to build factories from registry %w", err)
 	}
 
-	processors, err := component.MakeProcessorFactoryMap(
-		queuedprocessor.NewFactory(),
-		batchprocessor.NewFactory(),
-	)
+	otelCfg, err := unmarshalConf(conf, factories)
 	if err != nil {
-		return config.Factories{}, err
+		return nil, fmt.Errorf("failed to load OTel config %w", err)
 	}
 
-	return config.Factories{
-		Extensions: extensions,
-		Receivers:  receivers,
-		Processors: processors,
-		Exporters:  exporters,
-	}, nil
+	return otelCfg, nil
+}
+
+// componentTypeName strips the optional "/name" suffix off a component id
+// (e.g. "jaeger/collector" -> "jaeger") to get the registered component type.
+func componentTypeName(id string) string {
+	if idx := strings.Index(id, "/"); idx != -1 {
+		return id[:idx]
+	}
+	return id
 }
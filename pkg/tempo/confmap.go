@@ -0,0 +1,276 @@
+package tempo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/configmodels"
+	"gopkg.in/yaml.v3"
+)
+
+// Conf is an in-memory representation of configuration data, modeled after the
+// collector's own confmap.Conf. It lets several sources (the agent's tempo: YAML
+// block, optional files, env vars, ...) be merged together before being handed off
+// to the otel config loader.
+type Conf struct {
+	data map[string]interface{}
+}
+
+// NewConfFromStringMap builds a Conf from an already-assembled map, such as the one
+// tempo.Config.otelConfig builds from the agent's own YAML.
+func NewConfFromStringMap(data map[string]interface{}) *Conf {
+	return &Conf{data: data}
+}
+
+// ToStringMap returns the Conf's underlying representation.
+func (c *Conf) ToStringMap() map[string]interface{} {
+	return c.data
+}
+
+// Merge layers other on top of c, overwriting any keys they have in common.
+func (c *Conf) Merge(other *Conf) {
+	if other == nil {
+		return
+	}
+	mergeStringMaps(c.data, other.data)
+}
+
+func mergeStringMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]interface{}); ok {
+			if dstMap, ok := dst[k].(map[string]interface{}); ok {
+				mergeStringMaps(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// Provider retrieves configuration data from a source (a file, a remote store, ...)
+// to be merged into the confmap pipeline. Advanced users can implement their own
+// (for example, to pull secrets from a remote store) and register it via
+// Config.ConfigProvider.
+type Provider interface {
+	Retrieve(ctx context.Context) (*Conf, error)
+}
+
+// Converter mutates a Conf in place after all Providers have contributed to it - for
+// example, expanding ${ENV_VAR} references.
+type Converter interface {
+	Convert(ctx context.Context, conf *Conf) error
+}
+
+// ConfigProviderSettings allows advanced users to extend how the tempo subsystem
+// assembles its otel config: additional Providers are merged in after the agent's own
+// tempo: YAML block, and Converters run afterwards to do things like env var
+// expansion.
+type ConfigProviderSettings struct {
+	Providers  []Provider  `yaml:"-"`
+	Converters []Converter `yaml:"-"`
+}
+
+// defaultConverters returns the converters applied unless the user supplies their own.
+func defaultConverters() []Converter {
+	return []Converter{envExpandConverter{}}
+}
+
+// FileProvider reads a confmap from a YAML file on disk and merges it in. This is
+// useful for splitting a large tempo: block into includes.
+type FileProvider struct {
+	Path string
+}
+
+// Retrieve implements Provider.
+func (f FileProvider) Retrieve(_ context.Context) (*Conf, error) {
+	buf, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confmap file %s: %w", f.Path, err)
+	}
+
+	data := map[string]interface{}{}
+	if err := yaml.Unmarshal(buf, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse confmap file %s: %w", f.Path, err)
+	}
+
+	return NewConfFromStringMap(data), nil
+}
+
+// envExpandConverter expands ${ENV_VAR} references found in string values. Only the
+// ${VAR} form is substituted - a bare $VAR is left untouched, even if the same string
+// also contains a ${VAR} reference to the same name elsewhere.
+type envExpandConverter struct{}
+
+// Convert implements Converter.
+func (envExpandConverter) Convert(_ context.Context, conf *Conf) error {
+	conf.data = expandEnvInValue(conf.data).(map[string]interface{})
+	return nil
+}
+
+// envVarPattern matches only the braced ${VAR} form, so expandEnvInValue doesn't need
+// to infer (and can't get wrong) which textual form triggered a substitution the way
+// os.Expand's bare callback would.
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func expandEnvInValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return envVarPattern.ReplaceAllStringFunc(val, func(match string) string {
+			name := envVarPattern.FindStringSubmatch(match)[1]
+			return os.Getenv(name)
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			out[k] = expandEnvInValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, e := range val {
+			out[i] = expandEnvInValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// unmarshalConf decodes conf directly into a configmodels.Config, component by
+// component, using the factories the rest of this package already has on hand (see
+// Registry). Receivers/processors/exporters are each decoded via
+// factory.CreateDefaultConfig + mapstructure.Decode, the same pattern
+// buildExtensions uses for extensions - there's no intermediate viper.Viper or any
+// other bridge, since configmodels.Config is exactly the shape otelConfig needs.
+func unmarshalConf(conf *Conf, factories config.Factories) (*configmodels.Config, error) {
+	data := conf.ToStringMap()
+
+	cfg := &configmodels.Config{
+		Receivers:  configmodels.Receivers{},
+		Processors: configmodels.Processors{},
+		Exporters:  configmodels.Exporters{},
+		Service: configmodels.Service{
+			Pipelines: configmodels.Pipelines{},
+		},
+	}
+
+	if err := decodeReceivers(data["receivers"], factories.Receivers, cfg.Receivers); err != nil {
+		return nil, err
+	}
+	if err := decodeProcessors(data["processors"], factories.Processors, cfg.Processors); err != nil {
+		return nil, err
+	}
+	if err := decodeExporters(data["exporters"], factories.Exporters, cfg.Exporters); err != nil {
+		return nil, err
+	}
+	if err := decodePipelines(data["pipelines"], cfg.Service.Pipelines); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// unknownComponentTypeError reports that typ isn't one of known, naming every
+// registered component of that kind - the same "here's what's actually registered"
+// shape as Registry.ValidateReceiverTypes, extended to processors and exporters. kind
+// is singular (e.g. "receiver") and is pluralized for the list.
+func unknownComponentTypeError(kind string, typ configmodels.Type, known []string) error {
+	sort.Strings(known)
+	return fmt.Errorf("unknown %s type %q, registered %ss are: %s", kind, typ, kind, strings.Join(known, ", "))
+}
+
+func decodeReceivers(raw interface{}, factories map[configmodels.Type]component.ReceiverFactory, out configmodels.Receivers) error {
+	m, _ := raw.(map[string]interface{})
+	for name, rawCfg := range m {
+		typ := configmodels.Type(componentTypeName(name))
+		factory, ok := factories[typ]
+		if !ok {
+			known := make([]string, 0, len(factories))
+			for t := range factories {
+				known = append(known, string(t))
+			}
+			return unknownComponentTypeError("receiver", typ, known)
+		}
+
+		recvCfg := factory.CreateDefaultConfig()
+		if err := mapstructure.Decode(rawCfg, recvCfg); err != nil {
+			return fmt.Errorf("failed to decode config for receiver %q %w", name, err)
+		}
+		recvCfg.SetName(name)
+
+		out[name] = recvCfg
+	}
+	return nil
+}
+
+func decodeProcessors(raw interface{}, factories map[configmodels.Type]component.ProcessorFactory, out configmodels.Processors) error {
+	m, _ := raw.(map[string]interface{})
+	for name, rawCfg := range m {
+		typ := configmodels.Type(componentTypeName(name))
+		factory, ok := factories[typ]
+		if !ok {
+			known := make([]string, 0, len(factories))
+			for t := range factories {
+				known = append(known, string(t))
+			}
+			return unknownComponentTypeError("processor", typ, known)
+		}
+
+		procCfg := factory.CreateDefaultConfig()
+		if err := mapstructure.Decode(rawCfg, procCfg); err != nil {
+			return fmt.Errorf("failed to decode config for processor %q %w", name, err)
+		}
+		procCfg.SetName(name)
+
+		out[name] = procCfg
+	}
+	return nil
+}
+
+func decodeExporters(raw interface{}, factories map[configmodels.Type]component.ExporterFactory, out configmodels.Exporters) error {
+	m, _ := raw.(map[string]interface{})
+	for name, rawCfg := range m {
+		typ := configmodels.Type(componentTypeName(name))
+		factory, ok := factories[typ]
+		if !ok {
+			known := make([]string, 0, len(factories))
+			for t := range factories {
+				known = append(known, string(t))
+			}
+			return unknownComponentTypeError("exporter", typ, known)
+		}
+
+		expCfg := factory.CreateDefaultConfig()
+		if err := mapstructure.Decode(rawCfg, expCfg); err != nil {
+			return fmt.Errorf("failed to decode config for exporter %q %w", name, err)
+		}
+		expCfg.SetName(name)
+
+		out[name] = expCfg
+	}
+	return nil
+}
+
+func decodePipelines(raw interface{}, out configmodels.Pipelines) error {
+	m, _ := raw.(map[string]interface{})
+	for name, rawCfg := range m {
+		pipeline := &configmodels.Pipeline{
+			Name:      name,
+			InputType: configmodels.TracesDataType,
+		}
+		if err := mapstructure.Decode(rawCfg, pipeline); err != nil {
+			return fmt.Errorf("failed to decode pipeline %q %w", name, err)
+		}
+
+		out[name] = pipeline
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package tempo
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalConfDecodesComponentsAndSharesReceiverAcrossPipelines(t *testing.T) {
+	factories, err := CoreRegistry().Factories()
+	require.NoError(t, err)
+
+	conf := NewConfFromStringMap(map[string]interface{}{
+		"receivers": map[string]interface{}{
+			"jaeger": map[string]interface{}{},
+		},
+		"exporters": map[string]interface{}{
+			"otlp/a": map[string]interface{}{"endpoint": "example.com:1"},
+			"otlp/b": map[string]interface{}{"endpoint": "example.com:2"},
+		},
+		"processors": map[string]interface{}{},
+		"pipelines": map[string]interface{}{
+			"traces/a": map[string]interface{}{"receivers": []string{"jaeger"}, "exporters": []string{"otlp/a"}},
+			"traces/b": map[string]interface{}{"receivers": []string{"jaeger"}, "exporters": []string{"otlp/b"}},
+		},
+	})
+
+	cfg, err := unmarshalConf(conf, factories)
+	require.NoError(t, err)
+
+	assert.Len(t, cfg.Receivers, 1)
+	assert.Len(t, cfg.Exporters, 2)
+	assert.Len(t, cfg.Service.Pipelines, 2)
+	assert.Equal(t, []string{"jaeger"}, cfg.Service.Pipelines["traces/a"].Receivers)
+	assert.Equal(t, []string{"jaeger"}, cfg.Service.Pipelines["traces/b"].Receivers)
+}
+
+func TestUnmarshalConfUnknownReceiverTypeListsRegistered(t *testing.T) {
+	factories, err := CoreRegistry().Factories()
+	require.NoError(t, err)
+
+	conf := NewConfFromStringMap(map[string]interface{}{
+		"receivers": map[string]interface{}{"does-not-exist": map[string]interface{}{}},
+	})
+
+	_, err = unmarshalConf(conf, factories)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does-not-exist")
+	assert.Contains(t, err.Error(), "jaeger")
+}
+
+func TestUnmarshalConfUnknownExporterTypeListsRegistered(t *testing.T) {
+	factories, err := CoreRegistry().Factories()
+	require.NoError(t, err)
+
+	conf := NewConfFromStringMap(map[string]interface{}{
+		"exporters": map[string]interface{}{"does-not-exist": map[string]interface{}{}},
+	})
+
+	_, err = unmarshalConf(conf, factories)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does-not-exist")
+	assert.Contains(t, err.Error(), "otlp")
+}
+
+func TestExpandEnvInValueExpandsOnlyBracedForm(t *testing.T) {
+	require.NoError(t, os.Setenv("TEMPO_TEST_VAR", "expanded"))
+	defer os.Unsetenv("TEMPO_TEST_VAR")
+
+	out := expandEnvInValue("${TEMPO_TEST_VAR} and $TEMPO_TEST_VAR")
+	assert.Equal(t, "expanded and $TEMPO_TEST_VAR", out)
+}
+
+func TestEnvExpandConverterConvertsNestedValues(t *testing.T) {
+	require.NoError(t, os.Setenv("TEMPO_TEST_VAR", "expanded"))
+	defer os.Unsetenv("TEMPO_TEST_VAR")
+
+	conf := NewConfFromStringMap(map[string]interface{}{
+		"outer": map[string]interface{}{
+			"inner": []interface{}{"${TEMPO_TEST_VAR}", "$TEMPO_TEST_VAR"},
+		},
+	})
+
+	require.NoError(t, (envExpandConverter{}).Convert(context.Background(), conf))
+
+	inner := conf.ToStringMap()["outer"].(map[string]interface{})["inner"].([]interface{})
+	assert.Equal(t, "expanded", inner[0])
+	assert.Equal(t, "$TEMPO_TEST_VAR", inner[1])
+}
@@ -0,0 +1,128 @@
+package tempo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+	"go.opencensus.io/zpages"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.uber.org/zap"
+)
+
+const (
+	zpagesTypeStr      = "zpages"
+	healthCheckTypeStr = "health_check"
+)
+
+// mountedMuxPatterns tracks, per *http.ServeMux, which patterns buildExtensions has
+// already mounted zpages/health_check onto. http.ServeMux.Handle panics on a second
+// registration of the same pattern, and a Tempo subsystem can legitimately be rebuilt
+// against the same agent-owned mux - e.g. an operator enabling extensions: and
+// reloading, or a top-level agent reload that recreates Tempo entirely (Reload
+// itself can't rebuild extensions; see Reload's doc comment) - so a second buildExtensions
+// call against a mux that already has these routes mounted must no-op rather than panic.
+var (
+	mountedMuxMu       sync.Mutex
+	mountedMuxPatterns = map[*http.ServeMux]map[string]bool{}
+)
+
+// mountOnce calls mount to register pattern on mux, unless that exact (mux, pattern)
+// pair has already been mounted by an earlier buildExtensions call - in which case it
+// no-ops, leaving the previously mounted handler (functionally identical, since
+// zpages/health_check carry no per-Tempo state) in place.
+func mountOnce(mux *http.ServeMux, pattern string, mount func()) {
+	mountedMuxMu.Lock()
+	defer mountedMuxMu.Unlock()
+
+	if mountedMuxPatterns[mux] == nil {
+		mountedMuxPatterns[mux] = map[string]bool{}
+	}
+	if mountedMuxPatterns[mux][pattern] {
+		return
+	}
+
+	mount()
+	mountedMuxPatterns[mux][pattern] = true
+}
+
+// buildExtensions starts every extension configured under tempo.extensions.
+// zpages and health_check are mounted directly onto the agent's own HTTP mux rather
+// than going through the generic otel extension factories, since those factories
+// always bind their own listener off a configured endpoint - mounting them on mux
+// instead means their pages show up under the agent's existing HTTP server rather
+// than a second port the operator has to know about. Any other registered extension
+// still goes through the normal factory path and binds its own listener, recorded so
+// it can be surfaced through GetExtensions and shut down in Stop.
+//
+// These live once at the Tempo level rather than per-pipeline, since they're
+// collector-wide diagnostics rather than something scoped to a single trace
+// pipeline.
+func (t *Tempo) buildExtensions(ctx context.Context, cfg Config, mux *http.ServeMux) error {
+	if len(cfg.Extensions) == 0 {
+		return nil
+	}
+
+	factories, err := t.registry.Factories()
+	if err != nil {
+		return fmt.Errorf("failed to load factories from registry %w", err)
+	}
+
+	for name, rawCfg := range cfg.Extensions {
+		typ := componentTypeName(name)
+
+		switch typ {
+		case zpagesTypeStr:
+			if mux == nil {
+				return fmt.Errorf("extension %q requires an http mux but none was provided to tempo.New", name)
+			}
+			mountOnce(mux, "/debug/tempo", func() { zpages.Handle(mux, "/debug/tempo") })
+			continue
+		case healthCheckTypeStr:
+			if mux == nil {
+				return fmt.Errorf("extension %q requires an http mux but none was provided to tempo.New", name)
+			}
+			mountOnce(mux, "/ready", func() { mux.HandleFunc("/ready", t.handleHealthCheck) })
+			continue
+		}
+
+		factory, ok := factories.Extensions[configmodels.Type(typ)]
+		if !ok {
+			return fmt.Errorf("unknown extension type %q", typ)
+		}
+
+		extCfg := factory.CreateDefaultConfig()
+		if err := mapstructure.Decode(rawCfg, extCfg); err != nil {
+			return fmt.Errorf("failed to decode config for extension %q %w", name, err)
+		}
+
+		ext, err := factory.CreateExtension(ctx, component.ExtensionCreateParams{Logger: t.logger}, extCfg)
+		if err != nil {
+			return fmt.Errorf("failed to create extension %q %w", name, err)
+		}
+
+		if err := ext.Start(ctx, t); err != nil {
+			return fmt.Errorf("failed to start extension %q %w", name, err)
+		}
+
+		t.extensions[configmodels.Extension(name)] = ext
+	}
+
+	return nil
+}
+
+func (t *Tempo) handleHealthCheck(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("tempo ok"))
+}
+
+func (t *Tempo) stopExtensions(ctx context.Context) {
+	for name, ext := range t.extensions {
+		if err := ext.Shutdown(ctx); err != nil {
+			t.logger.Error("failed to shutdown extension", zap.String("extension", string(name)), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tempo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func configWithHealthCheckAndZpages() Config {
+	cfg := twoPipelineConfig("example.com:2")
+	cfg.Extensions = map[string]interface{}{
+		"health_check": map[string]interface{}{},
+		"zpages":       map[string]interface{}{},
+	}
+	return cfg
+}
+
+// TestBuildExtensionsServesHealthCheckAndZpages covers buildExtensions's mux-mounting
+// path end to end: /ready and /debug/tempo must actually respond once health_check
+// and zpages are configured.
+func TestBuildExtensionsServesHealthCheckAndZpages(t *testing.T) {
+	mux := http.NewServeMux()
+
+	tempo, err := New(configWithHealthCheckAndZpages(), log.NewNopLogger(), CoreRegistry(), nil, mux)
+	require.NoError(t, err)
+	defer tempo.Stop()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ready")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/debug/tempo/tracez")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// TestBuildExtensionsReregistrationOnSameMuxNoops is the regression test for the bug
+// where recreating a Tempo subsystem against the same agent-owned mux - e.g. Stop()
+// followed by New() again, as a top-level agent reload would do - panicked with
+// "multiple registrations for /ready" the second time health_check/zpages mounted
+// their routes onto it.
+func TestBuildExtensionsReregistrationOnSameMuxNoops(t *testing.T) {
+	mux := http.NewServeMux()
+	cfg := configWithHealthCheckAndZpages()
+
+	first, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, mux)
+	require.NoError(t, err)
+	first.Stop()
+
+	assert.NotPanics(t, func() {
+		second, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, mux)
+		require.NoError(t, err)
+		second.Stop()
+	})
+}
@@ -0,0 +1,87 @@
+package tempo
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newZapLogger bridges the agent's own go-kit logger into a *zap.Logger, so that
+// collector pipeline logs land in the agent's log stream - at their real level and
+// as plain keyvals - instead of a separate zap.NewProduction() logger that nobody
+// configures or sees.
+func newZapLogger(l log.Logger) *zap.Logger {
+	return zap.New(&gokitCore{logger: l, level: zap.NewAtomicLevel()})
+}
+
+// gokitCore is a zapcore.Core that forwards each zap entry to a go-kit log.Logger as
+// "msg"/"level" plus one keyval per field, rather than encoding the entry to bytes
+// and writing it through as a single opaque field - so a pipeline log's real
+// severity and structured fields survive the bridge instead of every line flattening
+// to one level=info line with a nested, double-escaped JSON blob.
+type gokitCore struct {
+	logger log.Logger
+	level  zap.AtomicLevel
+	fields []zapcore.Field
+}
+
+func (c *gokitCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+func (c *gokitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &gokitCore{
+		logger: c.logger,
+		level:  c.level,
+		fields: append(append([]zapcore.Field{}, c.fields...), fields...),
+	}
+}
+
+func (c *gokitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *gokitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	keyvals := make([]interface{}, 0, 4+2*len(enc.Fields))
+	keyvals = append(keyvals, "msg", ent.Message)
+	if ent.LoggerName != "" {
+		keyvals = append(keyvals, "logger", ent.LoggerName)
+	}
+	for k, v := range enc.Fields {
+		keyvals = append(keyvals, k, v)
+	}
+
+	return gokitLevelFor(ent.Level)(c.logger).Log(keyvals...)
+}
+
+func (c *gokitCore) Sync() error {
+	return nil
+}
+
+// gokitLevelFor maps a zap level to the go-kit/log/level helper that logs at the
+// closest matching severity - go-kit only distinguishes debug/info/warn/error, so
+// zap's DPanic/Panic/Fatal all collapse to error.
+func gokitLevelFor(lvl zapcore.Level) func(log.Logger) log.Logger {
+	switch {
+	case lvl < zapcore.InfoLevel:
+		return level.Debug
+	case lvl < zapcore.WarnLevel:
+		return level.Info
+	case lvl < zapcore.ErrorLevel:
+		return level.Warn
+	default:
+		return level.Error
+	}
+}
@@ -0,0 +1,59 @@
+package tempo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// capturingLogger is a go-kit log.Logger that records every Log call as a
+// keyval->value map, so tests can assert on what newZapLogger forwarded.
+type capturingLogger struct {
+	logs []map[string]interface{}
+}
+
+func (c *capturingLogger) Log(keyvals ...interface{}) error {
+	m := map[string]interface{}{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		m[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+	c.logs = append(c.logs, m)
+	return nil
+}
+
+// TestNewZapLoggerForwardsLevelAndFields is the regression test for the bug where
+// every zap entry - regardless of its real level - was JSON-encoded and written
+// through as a single opaque "msg" value at go-kit level.Info. Each entry's real
+// level and fields must come through as their own keyvals instead.
+func TestNewZapLoggerForwardsLevelAndFields(t *testing.T) {
+	captured := &capturingLogger{}
+	zl := newZapLogger(captured)
+
+	zl.Info("starting receiver", zap.String("component", "jaeger"))
+	zl.Error("failed to bind listener", zap.String("component", "jaeger"))
+
+	require.Len(t, captured.logs, 2)
+
+	assert.Equal(t, "info", fmt.Sprint(captured.logs[0]["level"]))
+	assert.Equal(t, "starting receiver", captured.logs[0]["msg"])
+	assert.Equal(t, "jaeger", captured.logs[0]["component"])
+
+	assert.Equal(t, "error", fmt.Sprint(captured.logs[1]["level"]))
+	assert.Equal(t, "failed to bind listener", captured.logs[1]["msg"])
+	assert.Equal(t, "jaeger", captured.logs[1]["component"])
+}
+
+// TestNewZapLoggerFiltersBelowInfoByDefault matches zap.NewAtomicLevel's default
+// (info) level, which newZapLogger relies on to keep debug-level collector chatter
+// out of the agent's log stream unless an operator explicitly wants it.
+func TestNewZapLoggerFiltersBelowInfoByDefault(t *testing.T) {
+	captured := &capturingLogger{}
+	zl := newZapLogger(captured)
+
+	zl.Debug("should be filtered")
+
+	assert.Empty(t, captured.logs)
+}
@@ -0,0 +1,191 @@
+package tempo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/exporter/jaegerexporter"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.opentelemetry.io/collector/processor/attributesprocessor"
+	"go.opentelemetry.io/collector/processor/batchprocessor"
+	"go.opentelemetry.io/collector/processor/queuedprocessor"
+	"go.opentelemetry.io/collector/processor/resourceprocessor"
+	"go.opentelemetry.io/collector/processor/spanprocessor"
+	"go.opentelemetry.io/collector/processor/tailsamplingprocessor"
+	"go.opentelemetry.io/collector/receiver/jaegerreceiver"
+	"go.opentelemetry.io/collector/receiver/kafkareceiver"
+	"go.opentelemetry.io/collector/receiver/opencensusreceiver"
+	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+	"go.opentelemetry.io/collector/receiver/zipkinreceiver"
+
+	"github.com/grafana/agent/pkg/tempo/automaticloggingprocessor"
+)
+
+// Registry holds the set of receiver/processor/exporter/extension factories a Tempo
+// pipeline is allowed to reference in its YAML. Unlike the old tracingFactories
+// function, components are added via registration rather than by editing this
+// package, so new components (including ones defined by other packages, like
+// automaticloggingprocessor) are a registration-only change.
+type Registry struct {
+	extensions map[configmodels.Type]component.ExtensionFactory
+	receivers  map[configmodels.Type]component.ReceiverFactory
+	processors map[configmodels.Type]component.ProcessorFactory
+	exporters  map[configmodels.Type]component.ExporterFactory
+}
+
+// NewRegistry returns an empty Registry. Most callers want CoreRegistry or
+// ContribRegistry instead.
+func NewRegistry() *Registry {
+	return &Registry{
+		extensions: map[configmodels.Type]component.ExtensionFactory{},
+		receivers:  map[configmodels.Type]component.ReceiverFactory{},
+		processors: map[configmodels.Type]component.ProcessorFactory{},
+		exporters:  map[configmodels.Type]component.ExporterFactory{},
+	}
+}
+
+// RegisterExtension adds an extension factory to the registry.
+func (r *Registry) RegisterExtension(f component.ExtensionFactory) {
+	r.extensions[f.Type()] = f
+}
+
+// RegisterReceiver adds a receiver factory to the registry.
+func (r *Registry) RegisterReceiver(f component.ReceiverFactory) {
+	r.receivers[f.Type()] = f
+}
+
+// RegisterProcessor adds a processor factory to the registry.
+func (r *Registry) RegisterProcessor(f component.ProcessorFactory) {
+	r.processors[f.Type()] = f
+}
+
+// RegisterExporter adds an exporter factory to the registry.
+func (r *Registry) RegisterExporter(f component.ExporterFactory) {
+	r.exporters[f.Type()] = f
+}
+
+// Factories converts the registry into the config.Factories shape the otel config
+// loader expects.
+func (r *Registry) Factories() (config.Factories, error) {
+	extensions, err := component.MakeExtensionFactoryMap(extensionFactorySlice(r.extensions)...)
+	if err != nil {
+		return config.Factories{}, err
+	}
+
+	receivers, err := component.MakeReceiverFactoryMap(receiverFactorySlice(r.receivers)...)
+	if err != nil {
+		return config.Factories{}, err
+	}
+
+	processors, err := component.MakeProcessorFactoryMap(processorFactorySlice(r.processors)...)
+	if err != nil {
+		return config.Factories{}, err
+	}
+
+	exporters, err := component.MakeExporterFactoryMap(exporterFactorySlice(r.exporters)...)
+	if err != nil {
+		return config.Factories{}, err
+	}
+
+	return config.Factories{
+		Extensions: extensions,
+		Receivers:  receivers,
+		Processors: processors,
+		Exporters:  exporters,
+	}, nil
+}
+
+// ValidateReceiverTypes returns a clear error naming every registered receiver type if
+// any type in typs isn't one of them.
+func (r *Registry) ValidateReceiverTypes(typs []configmodels.Type) error {
+	return validateTypes(typs, r.receivers)
+}
+
+func validateTypes(typs []configmodels.Type, known map[configmodels.Type]component.ReceiverFactory) error {
+	for _, typ := range typs {
+		if _, ok := known[typ]; !ok {
+			names := make([]string, 0, len(known))
+			for name := range known {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown receiver type %q, registered receivers are: %s", typ, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+func extensionFactorySlice(m map[configmodels.Type]component.ExtensionFactory) []component.ExtensionFactory {
+	out := make([]component.ExtensionFactory, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	return out
+}
+
+func receiverFactorySlice(m map[configmodels.Type]component.ReceiverFactory) []component.ReceiverFactory {
+	out := make([]component.ReceiverFactory, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	return out
+}
+
+func processorFactorySlice(m map[configmodels.Type]component.ProcessorFactory) []component.ProcessorFactory {
+	out := make([]component.ProcessorFactory, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	return out
+}
+
+func exporterFactorySlice(m map[configmodels.Type]component.ExporterFactory) []component.ExporterFactory {
+	out := make([]component.ExporterFactory, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	return out
+}
+
+// CoreRegistry returns the minimal set of components the agent has always shipped:
+// Jaeger/Zipkin/OTLP receivers, an OTLP exporter, and batch/queued processors.
+func CoreRegistry() *Registry {
+	r := NewRegistry()
+
+	r.RegisterReceiver(jaegerreceiver.NewFactory())
+	r.RegisterReceiver(&zipkinreceiver.Factory{})
+	r.RegisterReceiver(otlpreceiver.NewFactory())
+
+	r.RegisterExporter(&otlpexporter.Factory{})
+
+	r.RegisterProcessor(queuedprocessor.NewFactory())
+	r.RegisterProcessor(batchprocessor.NewFactory())
+
+	return r
+}
+
+// ContribRegistry extends CoreRegistry with components useful for integrating
+// existing trace pipelines: OpenCensus and Kafka receivers, a Jaeger exporter for
+// dual-writing during migrations, attribute/resource/span processors for scrubbing
+// PII and adding cluster labels, tail_sampling, and the agent's own
+// automatic_logging_processor.
+func ContribRegistry() *Registry {
+	r := CoreRegistry()
+
+	r.RegisterReceiver(opencensusreceiver.NewFactory())
+	r.RegisterReceiver(kafkareceiver.NewFactory())
+
+	r.RegisterExporter(jaegerexporter.NewFactory())
+
+	r.RegisterProcessor(attributesprocessor.NewFactory())
+	r.RegisterProcessor(resourceprocessor.NewFactory())
+	r.RegisterProcessor(spanprocessor.NewFactory())
+	r.RegisterProcessor(tailsamplingprocessor.NewFactory())
+	r.RegisterProcessor(automaticloggingprocessor.NewFactory())
+
+	return r
+}
@@ -0,0 +1,46 @@
+package tempo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/receiver/jaegerreceiver"
+)
+
+func TestRegistryFactoriesIncludesRegisteredReceiver(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterReceiver(jaegerreceiver.NewFactory())
+
+	factories, err := r.Factories()
+	require.NoError(t, err)
+
+	_, ok := factories.Receivers[configmodels.Type("jaeger")]
+	assert.True(t, ok)
+}
+
+func TestValidateReceiverTypesAcceptsRegisteredType(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterReceiver(jaegerreceiver.NewFactory())
+
+	err := r.ValidateReceiverTypes([]configmodels.Type{"jaeger"})
+	assert.NoError(t, err)
+}
+
+func TestValidateReceiverTypesRejectsUnregisteredTypeWithRegisteredListed(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterReceiver(jaegerreceiver.NewFactory())
+
+	err := r.ValidateReceiverTypes([]configmodels.Type{"does-not-exist"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does-not-exist")
+	assert.Contains(t, err.Error(), "jaeger")
+}
+
+func TestCoreRegistryRegistersExpectedReceivers(t *testing.T) {
+	r := CoreRegistry()
+
+	err := r.ValidateReceiverTypes([]configmodels.Type{"jaeger", "zipkin", "otlp"})
+	assert.NoError(t, err)
+}
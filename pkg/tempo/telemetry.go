@@ -0,0 +1,44 @@
+package tempo
+
+import (
+	"fmt"
+
+	ocprom "contrib.go.opencensus.io/exporter/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/obsreport"
+)
+
+// registerTelemetry wires the collector's internal OpenCensus instrumentation
+// (receiver accepted/refused spans, processor batch sizes/queue depths, exporter
+// sent/failed spans - all emitted by the collector's own obsreport package) into the
+// agent's Prometheus registry, so pipeline health shows up next to everything else
+// the agent exposes.
+//
+// reg may be nil, in which case telemetry is skipped - useful for tests and for
+// agents that don't want tempo metrics mixed into their registry.
+func registerTelemetry(reg prometheus.Registerer) (*ocprom.Exporter, error) {
+	if reg == nil {
+		return nil, nil
+	}
+
+	// Registering the exporter alone does nothing without the views it's meant to
+	// export - these are the obsreport view sets the collector's receivers,
+	// processors and exporters record their own stats against.
+	views := append(append(obsreport.ReceiverViews(), obsreport.ProcessorViews()...), obsreport.ExporterViews()...)
+	if err := view.Register(views...); err != nil {
+		return nil, fmt.Errorf("failed to register obsreport views %w", err)
+	}
+
+	exporter, err := ocprom.NewExporter(ocprom.Options{
+		Namespace:  "tempo",
+		Registerer: reg,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opencensus->prometheus exporter %w", err)
+	}
+
+	view.RegisterExporter(exporter)
+
+	return exporter, nil
+}
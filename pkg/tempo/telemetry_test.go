@@ -0,0 +1,64 @@
+package tempo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats"
+	"go.opentelemetry.io/collector/obsreport"
+)
+
+// TestRegisterTelemetryExposesPrometheusMetrics covers registerTelemetry's actual
+// deliverable: obsreport views recorded by the collector's receivers/processors/
+// exporters must show up as "tempo_"-namespaced metrics on the agent's own
+// prometheus.Registerer, not just register without error.
+func TestRegisterTelemetryExposesPrometheusMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	exporter, err := registerTelemetry(reg)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+
+	views := append(append(obsreport.ReceiverViews(), obsreport.ProcessorViews()...), obsreport.ExporterViews()...)
+	require.NotEmpty(t, views)
+
+	recorded := false
+	for _, v := range views {
+		switch m := v.Measure.(type) {
+		case *stats.Int64Measure:
+			require.NoError(t, stats.Record(context.Background(), m.M(1)))
+			recorded = true
+		case *stats.Float64Measure:
+			require.NoError(t, stats.Record(context.Background(), m.M(1)))
+			recorded = true
+		}
+		if recorded {
+			break
+		}
+	}
+	require.True(t, recorded, "expected at least one obsreport view to have a recordable int64/float64 measure")
+
+	metrics, err := reg.Gather()
+	require.NoError(t, err)
+
+	found := false
+	for _, mf := range metrics {
+		if strings.HasPrefix(mf.GetName(), "tempo_") {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "expected at least one tempo_ prefixed metric after recording an obsreport measurement")
+}
+
+// TestRegisterTelemetrySkipsNilRegisterer documents the nil-Registerer fast path
+// every existing tempo_test.go call site relies on (New(..., nil, ...)).
+func TestRegisterTelemetrySkipsNilRegisterer(t *testing.T) {
+	exporter, err := registerTelemetry(nil)
+	require.NoError(t, err)
+	assert.Nil(t, exporter)
+}
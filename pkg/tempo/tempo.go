@@ -3,8 +3,11 @@ package tempo
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"reflect"
 
 	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"go.opentelemetry.io/collector/component"
@@ -18,104 +21,310 @@ tempo:
   receivers:
     jaeger:
       ...
-  remote_write:
-    url: doesntexist:12345
-    batch_config:
-      send_batch_size: 1024
-      timeout: 5s
+  pipelines:
+    - name: grafana-cloud
+      receivers: [jaeger]
+      remote_write:
+        url: doesntexist:12345
+        batch:
+          send_batch_size: 1024
+          timeout: 5s
 */
 
 // Tempo wraps the OpenTelemetry collector to enablet tracing pipelines
 type Tempo struct {
 	logger *zap.Logger
 
+	registry *Registry
+	cfg      Config
+
+	extensions map[configmodels.Extension]component.ServiceExtension
+
+	// builds holds one *pipelineBuild per connected component of cfg's
+	// pipeline/receiver reference graph (see Config.pipelineComponents). Every
+	// receiver in a component is shared by every pipeline in it, so the whole
+	// component is built, started and torn down as a unit - but components are
+	// otherwise independent, which is what lets Reload rebuild the one component
+	// that changed without disturbing any other pipeline's receivers.
+	builds []*pipelineBuild
+}
+
+// pipelineBuild is the exporter/pipelines/receivers built for a single connected
+// component of the pipeline/receiver reference graph - i.e. one or more
+// PipelineConfigs that all transitively share a receiver, built against an
+// otelConfig scoped to just that subset. names is the sorted set of pipeline
+// names covered by this build, as returned by Config.pipelineComponents.
+type pipelineBuild struct {
+	names []string
+
 	exporter  builder.Exporters
 	pipelines builder.BuiltPipelines
 	receivers builder.Receivers
 }
 
-// New creates and starts Loki log collection.
-func New(cfg Config, l log.Logger) (*Tempo, error) { // jpe what do with logger?
-	var err error
+func (b *pipelineBuild) start(ctx context.Context, host component.Host) error {
+	if err := b.exporter.StartAll(ctx, host); err != nil {
+		return fmt.Errorf("failed to start exporters %w", err)
+	}
+	if err := b.pipelines.StartProcessors(ctx, host); err != nil {
+		return fmt.Errorf("failed to start processors %w", err)
+	}
+	// receivers are built once against this component's combined config, so a
+	// receiver referenced by more than one pipeline in it fans out to all of them
+	// instead of being instantiated once per pipeline.
+	if err := b.receivers.StartAll(ctx, host); err != nil {
+		return fmt.Errorf("failed to start receivers %w", err)
+	}
+	return nil
+}
 
-	tempo := &Tempo{}
-	tempo.logger, err = zap.NewProduction()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create zap prod logger %w", err)
+func (b *pipelineBuild) stop(ctx context.Context, logger *zap.Logger) {
+	if err := b.receivers.ShutdownAll(ctx); err != nil {
+		logger.Error("failed to shutdown receivers", zap.Strings("pipelines", b.names), zap.Error(err))
+	}
+	if err := b.pipelines.ShutdownProcessors(ctx); err != nil {
+		logger.Error("failed to shutdown processors", zap.Strings("pipelines", b.names), zap.Error(err))
+	}
+	if err := b.exporter.ShutdownAll(ctx); err != nil {
+		logger.Error("failed to shutdown exporters", zap.Strings("pipelines", b.names), zap.Error(err))
+	}
+}
+
+// New creates and starts the configured tempo pipelines. reg controls which
+// receiver/processor/exporter types the agent's tempo: YAML block is allowed to
+// reference - pass CoreRegistry() or ContribRegistry(), or a Registry assembled from
+// both plus any custom factories. promReg is where the collector's internal
+// receiver/processor/exporter metrics are registered; pass nil to skip. mux is the
+// agent's own HTTP server mux, used to serve the zpages/health_check extensions
+// (if configured) alongside the rest of the agent's HTTP endpoints; pass nil if
+// tempo.extensions won't reference either.
+func New(cfg Config, l log.Logger, reg *Registry, promReg prometheus.Registerer, mux *http.ServeMux) (*Tempo, error) {
+	tempo := &Tempo{
+		logger:     newZapLogger(l),
+		registry:   reg,
+		extensions: map[configmodels.Extension]component.ServiceExtension{},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tempo config %w", err)
+	}
+
+	if _, err := registerTelemetry(promReg); err != nil {
+		return nil, fmt.Errorf("failed to register tempo telemetry %w", err)
 	}
 
 	createCtx := context.Background()
-	err = tempo.buildAndStartPipeline(createCtx, cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create exporter %w", err)
+
+	if err := tempo.buildExtensions(createCtx, cfg, mux); err != nil {
+		return nil, fmt.Errorf("failed to build extensions %w", err)
+	}
+
+	if _, err := tempo.buildAndStartPipelines(createCtx, cfg, cfg.pipelineComponents()); err != nil {
+		return nil, fmt.Errorf("failed to create pipelines %w", err)
 	}
+	tempo.cfg = cfg
 
 	return tempo, nil
 }
 
-// Stop stops the OpenTelemetry collector subsystem
+// Stop stops every pipeline and extension the OpenTelemetry collector subsystem is
+// running, in dependency order: receivers first (so no new spans enter), then
+// pipeline processors, then exporters.
 func (t *Tempo) Stop() {
 	shutdownCtx := context.Background()
 
-	if err := t.receivers.ShutdownAll(shutdownCtx); err != nil {
-		t.logger.Error("failed to shutdown receiver", zap.Error(err))
+	for _, b := range t.builds {
+		b.stop(shutdownCtx, t.logger)
 	}
 
-	if err := t.pipelines.ShutdownProcessors(shutdownCtx); err != nil {
-		t.logger.Error("failed to shutdown processors", zap.Error(err))
+	t.stopExtensions(shutdownCtx)
+}
+
+// Reload replaces the running config with cfg. Pipelines are grouped into
+// connected components by shared receiver (see Config.pipelineComponents), and each
+// component is diffed independently via Config.snapshotFor: a component whose own
+// slice of the config (its pipelines plus the receivers they reference) is
+// unchanged keeps running untouched, while only the component(s) that actually
+// changed are torn down and rebuilt. This keeps receivers shared and built once per
+// component - avoiding the "rebuild per pipeline" bug that double-binds a shared
+// receiver's listen address - without forcing every pipeline in the agent to drop
+// spans whenever any one of them is reloaded.
+//
+// The replaced components' old builds are only stopped once their replacements are
+// built and started successfully - if a rebuild fails (a bad receiver reference
+// surfaced late, a listener bind failure, ...), the old builds are left running
+// untouched and Reload returns the error, rather than dropping spans on a component
+// that otherwise would have kept working.
+//
+// Reload only ever touches pipelines/receivers - it cannot rebuild cfg.Extensions,
+// since zpages/health_check are mounted once onto the agent's mux for the life of
+// the process and the generic extension factories have no "reconfigure" hook of
+// their own. A changed Extensions block is rejected outright rather than silently
+// kept running against the old config: recreate the Tempo subsystem (Stop then New)
+// to pick up an extensions change.
+func (t *Tempo) Reload(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid tempo config %w", err)
 	}
 
-	if err := t.receivers.ShutdownAll(shutdownCtx); err != nil {
-		t.logger.Error("failed to shutdown receivers", zap.Error(err))
+	if reflect.DeepEqual(cfg, t.cfg) {
+		return nil
 	}
-}
 
-func (t *Tempo) buildAndStartPipeline(ctx context.Context, cfg Config) error {
-	// create component factories
-	otelConfig, err := cfg.otelConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load otelConfig from agent tempo config %w", err)
+	if !reflect.DeepEqual(cfg.Extensions, t.cfg.Extensions) {
+		return fmt.Errorf("tempo extensions config changed - restart the agent to apply it, Reload cannot rebuild extensions")
 	}
 
-	factories, err := tracingFactories()
-	if err != nil {
-		return fmt.Errorf("failed to load tracing factories %w", err)
+	newGroups := cfg.pipelineComponents()
+
+	unchanged := map[*pipelineBuild]bool{}
+	var toBuild [][]string
+	for _, names := range newGroups {
+		if b := t.findUnchangedBuild(names, cfg); b != nil {
+			unchanged[b] = true
+			continue
+		}
+		toBuild = append(toBuild, names)
 	}
 
-	// start exporter
-	t.exporter, err = builder.NewExportersBuilder(t.logger, otelConfig, factories.Exporters).Build()
-	if err != nil {
-		return fmt.Errorf("failed to build exporters %w", err)
+	// snapshot the builds being replaced before buildAndStartPipelines starts
+	// mutating t.builds, so they can be stopped once (and only once) the
+	// replacements are confirmed good.
+	var stale []*pipelineBuild
+	for _, b := range t.builds {
+		if !unchanged[b] {
+			stale = append(stale, b)
+		}
 	}
 
-	err = t.exporter.StartAll(ctx, t)
-	if err != nil {
-		return fmt.Errorf("failed to start exporters %w", err)
+	if _, err := t.buildAndStartPipelines(context.Background(), cfg, toBuild); err != nil {
+		return fmt.Errorf("failed to rebuild pipelines %w", err)
 	}
 
-	// start pipelines
-	t.pipelines, err = builder.NewPipelinesBuilder(t.logger, otelConfig, t.exporter, factories.Processors).Build()
-	if err != nil {
-		return fmt.Errorf("failed to build exporters %w", err)
+	shutdownCtx := context.Background()
+	for _, b := range stale {
+		b.stop(shutdownCtx, t.logger)
+		t.removeBuild(b)
 	}
 
-	err = t.pipelines.StartProcessors(ctx, t)
-	if err != nil {
-		return fmt.Errorf("failed to start processors %w", err)
+	t.cfg = cfg
+
+	return nil
+}
+
+// findUnchangedBuild returns the currently running build covering exactly the
+// pipeline names in group, if that group's relevant slice of cfg is identical to
+// what that build was last built from - i.e. whether this group can be left
+// running as-is rather than torn down and rebuilt.
+func (t *Tempo) findUnchangedBuild(names []string, cfg Config) *pipelineBuild {
+	for _, b := range t.builds {
+		if !sameNames(b.names, names) {
+			continue
+		}
+		if reflect.DeepEqual(cfg.snapshotFor(names), t.cfg.snapshotFor(names)) {
+			return b
+		}
+		return nil
 	}
+	return nil
+}
 
-	// start receivers
-	t.receivers, err = builder.NewReceiversBuilder(t.logger, otelConfig, t.pipelines, factories.Receivers).Build()
-	if err != nil {
-		return fmt.Errorf("failed to start receivers %w", err)
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
 	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
 
-	err = t.receivers.StartAll(ctx, t)
+// buildAndStartPipelines builds and starts one pipelineBuild per group in groups,
+// where each group is a connected component of the pipeline/receiver reference
+// graph (Config.pipelineComponents) - i.e. the full set of pipeline names sharing a
+// receiver, built together against an otelConfig scoped to just that subset so its
+// receivers are instantiated exactly once.
+//
+// Each build is appended to t.builds as soon as it's constructed but before it is
+// started, so that GetExporters (implementing component.Host) already reflects it -
+// and every sibling group built earlier in this same call - while its processors
+// and receivers start. That's what lets a processor like tail_sampling resolve its
+// own pipeline's downstream exporter via host.GetExporters() during b.start. If any
+// group fails to build or start, every group already appended earlier in this call
+// is stopped and removed from t.builds again before the error is returned, so the
+// caller is left with exactly the builds it started with.
+func (t *Tempo) buildAndStartPipelines(ctx context.Context, cfg Config, groups [][]string) ([]*pipelineBuild, error) {
+	factories, err := t.registry.Factories()
 	if err != nil {
-		return fmt.Errorf("failed to start receivers %w", err)
+		return nil, fmt.Errorf("failed to load tracing factories %w", err)
 	}
 
-	return nil
+	built := make([]*pipelineBuild, 0, len(groups))
+	for _, names := range groups {
+		namesSet := make(map[string]bool, len(names))
+		for _, n := range names {
+			namesSet[n] = true
+		}
+
+		otelConfig, err := cfg.otelConfigFor(t.registry, namesSet)
+		if err != nil {
+			t.rollbackBuilds(built)
+			return nil, fmt.Errorf("failed to load otelConfig from agent tempo config %w", err)
+		}
+
+		b := &pipelineBuild{names: names}
+
+		b.exporter, err = builder.NewExportersBuilder(t.logger, otelConfig, factories.Exporters).Build()
+		if err != nil {
+			t.rollbackBuilds(built)
+			return nil, fmt.Errorf("failed to build exporters %w", err)
+		}
+
+		b.pipelines, err = builder.NewPipelinesBuilder(t.logger, otelConfig, b.exporter, factories.Processors).Build()
+		if err != nil {
+			t.rollbackBuilds(built)
+			return nil, fmt.Errorf("failed to build pipelines %w", err)
+		}
+
+		b.receivers, err = builder.NewReceiversBuilder(t.logger, otelConfig, b.pipelines, factories.Receivers).Build()
+		if err != nil {
+			t.rollbackBuilds(built)
+			return nil, fmt.Errorf("failed to build receivers %w", err)
+		}
+
+		t.builds = append(t.builds, b)
+		if err := b.start(ctx, t); err != nil {
+			t.removeBuild(b)
+			t.rollbackBuilds(built)
+			return nil, err
+		}
+
+		built = append(built, b)
+	}
+
+	return built, nil
+}
+
+// rollbackBuilds stops every build in built and removes it from t.builds, undoing
+// a partially-successful buildAndStartPipelines call.
+func (t *Tempo) rollbackBuilds(built []*pipelineBuild) {
+	shutdownCtx := context.Background()
+	for _, b := range built {
+		b.stop(shutdownCtx, t.logger)
+		t.removeBuild(b)
+	}
+}
+
+// removeBuild removes target from t.builds, if present.
+func (t *Tempo) removeBuild(target *pipelineBuild) {
+	for i, b := range t.builds {
+		if b == target {
+			t.builds = append(t.builds[:i], t.builds[i+1:]...)
+			return
+		}
+	}
 }
 
 // ReportFatalError implements component.Host
@@ -128,12 +337,26 @@ func (t *Tempo) GetFactory(kind component.Kind, componentType configmodels.Type)
 	return nil
 }
 
-// GetExtensions implements component.Host
+// GetExtensions implements component.Host, returning the extensions started from
+// tempo.extensions through their factories. zpages and health_check are mounted
+// directly on the agent's mux and are not included.
 func (t *Tempo) GetExtensions() map[configmodels.Extension]component.ServiceExtension {
-	return nil
+	return t.extensions
 }
 
-// GetExporters implements component.Host
+// GetExporters implements component.Host, merging the exporters of every
+// independently-built pipeline component.
 func (t *Tempo) GetExporters() map[configmodels.DataType]map[configmodels.Exporter]component.Exporter {
-	return nil
+	out := map[configmodels.DataType]map[configmodels.Exporter]component.Exporter{}
+	for _, b := range t.builds {
+		for dt, exps := range b.exporter.ToMapByDataType() {
+			if out[dt] == nil {
+				out[dt] = map[configmodels.Exporter]component.Exporter{}
+			}
+			for name, exp := range exps {
+				out[dt][name] = exp
+			}
+		}
+	}
+	return out
 }
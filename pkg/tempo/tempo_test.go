@@ -0,0 +1,185 @@
+package tempo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/config/configmodels"
+)
+
+func twoPipelineConfig(urlB string) Config {
+	return Config{
+		Enabled: true,
+		Receivers: map[string]interface{}{
+			"jaeger": map[string]interface{}{},
+			"zipkin": map[string]interface{}{},
+		},
+		Pipelines: []PipelineConfig{
+			{Name: "a", Receivers: []string{"jaeger"}, RemoteWrite: RWConfig{URL: "example.com:1"}},
+			{Name: "b", Receivers: []string{"zipkin"}, RemoteWrite: RWConfig{URL: urlB}},
+		},
+	}
+}
+
+// TestReloadLeavesUnrelatedPipelineRunning is the regression test for the bug where
+// Reload rebuilt every pipeline (tearing down every receiver, including ones with no
+// config change) on any single pipeline's change. "a" and "b" share no receiver, so
+// they land in separate components (see Config.pipelineComponents); reloading only
+// "b"'s remote_write URL must leave "a"'s build running untouched.
+func TestReloadLeavesUnrelatedPipelineRunning(t *testing.T) {
+	cfg := twoPipelineConfig("example.com:2")
+
+	tempo, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, nil)
+	require.NoError(t, err)
+	defer tempo.Stop()
+
+	require.Len(t, tempo.builds, 2)
+	var buildA, buildBBefore *pipelineBuild
+	for _, b := range tempo.builds {
+		if sameNames(b.names, []string{"a"}) {
+			buildA = b
+		}
+		if sameNames(b.names, []string{"b"}) {
+			buildBBefore = b
+		}
+	}
+	require.NotNil(t, buildA)
+	require.NotNil(t, buildBBefore)
+
+	err = tempo.Reload(twoPipelineConfig("example.com:3"))
+	require.NoError(t, err)
+
+	require.Len(t, tempo.builds, 2)
+	var buildAAfter, buildBAfter *pipelineBuild
+	for _, b := range tempo.builds {
+		if sameNames(b.names, []string{"a"}) {
+			buildAAfter = b
+		}
+		if sameNames(b.names, []string{"b"}) {
+			buildBAfter = b
+		}
+	}
+	require.NotNil(t, buildAAfter)
+	require.NotNil(t, buildBAfter)
+
+	assert.Same(t, buildA, buildAAfter, "pipeline a's config didn't change, its build should be left running")
+	assert.NotSame(t, buildBBefore, buildBAfter, "pipeline b's config changed, its build should be rebuilt")
+}
+
+// TestReloadLeavesStaleBuildRunningWhenRebuildFails is the regression test for the
+// bug where Reload stopped a changed component's old build before attempting its
+// replacement: a failure past Config.Validate() (here, a pipeline referencing a
+// receiver that doesn't exist) left that pipeline's build torn down with nothing
+// rebuilt in its place. The old build for "b" must still be running, unchanged,
+// after the failed Reload.
+func TestReloadLeavesStaleBuildRunningWhenRebuildFails(t *testing.T) {
+	cfg := twoPipelineConfig("example.com:2")
+
+	tempo, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, nil)
+	require.NoError(t, err)
+	defer tempo.Stop()
+
+	var buildBBefore *pipelineBuild
+	for _, b := range tempo.builds {
+		if sameNames(b.names, []string{"b"}) {
+			buildBBefore = b
+		}
+	}
+	require.NotNil(t, buildBBefore)
+
+	badCfg := twoPipelineConfig("example.com:2")
+	badCfg.Pipelines[1].Receivers = []string{"does-not-exist"}
+
+	err = tempo.Reload(badCfg)
+	require.Error(t, err)
+
+	assert.Equal(t, cfg, tempo.cfg, "the failed config must not be adopted")
+	require.Len(t, tempo.builds, 2)
+	var buildAAfter, buildBAfter *pipelineBuild
+	for _, b := range tempo.builds {
+		if sameNames(b.names, []string{"a"}) {
+			buildAAfter = b
+		}
+		if sameNames(b.names, []string{"b"}) {
+			buildBAfter = b
+		}
+	}
+	require.NotNil(t, buildAAfter)
+	assert.Same(t, buildBBefore, buildBAfter, "b's stale build must be left running since its rebuild failed")
+}
+
+// TestGetExportersMergesEveryComponent checks that, once New returns, GetExporters
+// merges the exporters of every independently-built component: "a" and "b" share no
+// receiver, so they are separate builds, and both "otlp/a" and "otlp/b" must be
+// listed under the traces data type.
+func TestGetExportersMergesEveryComponent(t *testing.T) {
+	cfg := twoPipelineConfig("example.com:2")
+
+	tempo, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, nil)
+	require.NoError(t, err)
+	defer tempo.Stop()
+
+	exporters := tempo.GetExporters()
+	require.Contains(t, exporters, configmodels.TracesDataType)
+
+	traceExporters := exporters[configmodels.TracesDataType]
+	names := make([]string, 0, len(traceExporters))
+	for exp := range traceExporters {
+		names = append(names, exp.Name())
+	}
+	assert.ElementsMatch(t, []string{"otlp/a", "otlp/b"}, names)
+}
+
+// TestReloadRejectsExtensionsChange is the regression test for the gap where Reload
+// only ever diffed/rebuilt pipelines: changing cfg.Extensions and calling Reload
+// silently kept the old extensions running against the stale config, with no error
+// surfaced. Reload can't rebuild extensions (see its doc comment), so it must reject
+// the change outright instead of pretending to have applied it.
+func TestReloadRejectsExtensionsChange(t *testing.T) {
+	cfg := twoPipelineConfig("example.com:2")
+	cfg.Extensions = map[string]interface{}{"health_check": map[string]interface{}{}}
+
+	tempo, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, http.NewServeMux())
+	require.NoError(t, err)
+	defer tempo.Stop()
+
+	changed := twoPipelineConfig("example.com:2")
+	changed.Extensions = map[string]interface{}{}
+
+	err = tempo.Reload(changed)
+	require.Error(t, err)
+	assert.Equal(t, cfg, tempo.cfg, "the rejected config must not be adopted")
+}
+
+func TestReloadNoopWhenConfigUnchanged(t *testing.T) {
+	cfg := twoPipelineConfig("example.com:2")
+
+	tempo, err := New(cfg, log.NewNopLogger(), CoreRegistry(), nil, nil)
+	require.NoError(t, err)
+	defer tempo.Stop()
+
+	before := tempo.builds
+
+	require.NoError(t, tempo.Reload(twoPipelineConfig("example.com:2")))
+
+	assert.Equal(t, before, tempo.builds)
+}
+
+func TestPipelineComponentsGroupsSharedReceiversTogether(t *testing.T) {
+	cfg := Config{
+		Pipelines: []PipelineConfig{
+			{Name: "a", Receivers: []string{"jaeger"}},
+			{Name: "b", Receivers: []string{"jaeger"}},
+			{Name: "c", Receivers: []string{"zipkin"}},
+		},
+	}
+
+	groups := cfg.pipelineComponents()
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"a", "b"}, groups[0])
+	assert.Equal(t, []string{"c"}, groups[1])
+}